@@ -7,28 +7,38 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/url"
+	"os"
+	"path/filepath"
 	"sort"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/juju/cmd"
 	"gopkg.in/errgo.v1"
 	"gopkg.in/juju/charm.v6-unstable"
 	"gopkg.in/juju/charmrepo.v2-unstable/csclient/params"
+	"gopkg.in/yaml.v2"
 	"launchpad.net/gnuflag"
-	"text/tabwriter"
-	"strings"
+
+	"github.com/juju/charmstore-client/internal/metadecoders"
 )
 
 type showCommand struct {
 	cmd.CommandBase
 
-	out      cmd.Output
-	channel  chanValue
-	id       *charm.URL
-	includes []string
-	list     bool
-	all      bool
-	summary  bool
+	out       cmd.Output
+	channel   chanValue
+	id        *charm.URL
+	includes  []string
+	list      bool
+	all       bool
+	summary   bool
+	config    bool
+	resources bool
+	convert   string
+	outDir    string
 
 	auth authInfo
 }
@@ -51,6 +61,19 @@ To specify one or more specific metadatas:
 To get a list of metadata available:
 
    charm show --list
+
+To see the charm's configuration options:
+
+   charm show wordpress --config
+
+To see the charm's resources and the channels publishing each revision:
+
+   charm show wordpress --resources
+
+To write the charm's metadata, config and metrics out as front-matter
+files suitable for a charm source tree:
+
+   charm show wordpress --convert=yaml --out ./wordpress
 `
 
 var DEFAULT_SUMMARY_FIELDS = []string{
@@ -77,6 +100,10 @@ func (c *showCommand) SetFlags(f *gnuflag.FlagSet) {
 	})
 	f.BoolVar(&c.list, "list", false, "list available metadata endpoints")
 	f.BoolVar(&c.all, "all", false, "show all data from the charm or bundle")
+	f.BoolVar(&c.config, "config", false, "show the charm's configuration options")
+	f.BoolVar(&c.resources, "resources", false, "show the charm's resources and the channels publishing them")
+	f.StringVar(&c.convert, "convert", "", "write metadata, config and metrics as toml, json or yaml files (requires --out)")
+	f.StringVar(&c.outDir, "out", "", "directory to write --convert output to")
 	addAuthFlag(f, &c.auth)
 	addChannelFlag(f, &c.channel, nil)
 }
@@ -101,6 +128,23 @@ func (c *showCommand) Init(args []string) error {
 		c.includes = DEFAULT_SUMMARY_FIELDS
 		c.summary = true
 	}
+	if c.config {
+		c.includes = append(c.includes, "charm-config")
+	}
+	if c.resources {
+		c.includes = append(c.includes, "resources", "published")
+	}
+	if c.convert != "" {
+		switch metadecoders.Format(c.convert) {
+		case metadecoders.YAML, metadecoders.JSON, metadecoders.TOML:
+		default:
+			return errgo.Newf("invalid --convert format %q, must be one of toml, json, yaml", c.convert)
+		}
+		if c.outDir == "" {
+			return errgo.New("--convert requires --out to be specified")
+		}
+		c.includes = append(c.includes, "charm-metadata", "charm-config", "charm-metrics")
+	}
 
 	id, err := charm.ParseURL(args[0])
 	if err != nil {
@@ -156,6 +200,36 @@ func (c *showCommand) Run(ctxt *cmd.Context) error {
 			delete(result.Meta, "common-info")
 		}
 	}
+	if c.convert != "" {
+		return writeCharmFiles(result.Meta, metadecoders.Format(c.convert), c.outDir)
+	}
+	if c.config {
+		result.Meta["config"] = parseConfigOptions(result.Meta)
+	}
+	var channels []channelInfo
+	if c.summary || c.config || c.resources {
+		if _, ok := result.Meta["published"]; ok {
+			channels = parseChannels(result.Meta)
+			revisions, err := fetchChannelRevisions(client, c.id, channels)
+			if err != nil {
+				return errgo.Notef(err, "cannot get per-channel revisions")
+			}
+			for i := range channels {
+				channels[i].Revision = revisions[channels[i].key()]
+			}
+			result.Meta["channels"] = channels
+			delete(result.Meta, "published")
+		}
+	}
+	if c.resources {
+		channelResources, err := fetchChannelResources(client, c.id, channels)
+		if err != nil {
+			return errgo.Notef(err, "cannot get per-channel resources")
+		}
+		result.Meta["channel-resources"] = channelResources
+		result.Meta["resources"] = parseResources(result.Meta)
+		delete(result.Meta, "channel-resources")
+	}
 	return c.out.Write(ctxt, result.Meta)
 }
 
@@ -227,11 +301,269 @@ type showData struct {
 	homePage        string
 	read            []string
 	write           []string
-	channels        []interface{}
+	channels        []channelInfo
 	bundle          bool
+	config          []configOption
+	resources       []resourceInfo
 	tw              *tabwriter.Writer
 }
 
+// channelInfo holds the data displayed for a single row of the channels
+// table in "charm show".
+type channelInfo struct {
+	Track    string `json:"track" yaml:"track"`
+	Risk     string `json:"risk" yaml:"risk"`
+	Current  bool   `json:"current" yaml:"current"`
+	Revision int    `json:"revision" yaml:"revision"`
+
+	// channel is the channel string as published by the store (e.g.
+	// "stable" for the implicit "latest" track), used to query
+	// per-channel endpoints. The store does not recognize the
+	// reconstructed "track/risk" form for implicit-track channels, so
+	// this must be preserved rather than rebuilt from Track and Risk.
+	channel string
+}
+
+// key returns the canonical "track/risk" identifier for the channel, as
+// used to key the per-channel lookup maps built by fetchChannelResources
+// and fetchChannelRevisions.
+func (c channelInfo) key() string {
+	return c.Track + "/" + c.Risk
+}
+
+// riskOrder gives the relative ordering of the well known channel risks,
+// most stable to least stable, as used to sort the channels table.
+var riskOrder = map[string]int{
+	"stable":    0,
+	"candidate": 1,
+	"beta":      2,
+	"edge":      3,
+}
+
+// parseChannel splits a channel string of the form "track/risk" into its
+// track and risk components. If no track is present, the implicit
+// "latest" track is assumed, matching the charmhub/snap channel spec.
+func parseChannel(s string) (track, risk string) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	return "latest", parts[0]
+}
+
+type byTrackAndRisk []channelInfo
+
+func (b byTrackAndRisk) Len() int      { return len(b) }
+func (b byTrackAndRisk) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byTrackAndRisk) Less(i, j int) bool {
+	if b[i].Track != b[j].Track {
+		return b[i].Track < b[j].Track
+	}
+	return riskOrder[b[i].Risk] < riskOrder[b[j].Risk]
+}
+
+// parseChannels converts the raw "published" metadata into a sorted slice
+// of channelInfo, grouped by track and ordered by risk within each track.
+// If the metadata already carries a precomputed "channels" value (as Run
+// sets when it has already done this work), that value is returned as
+// is, so re-deriving it from "published" downstream (e.g. in the tabular
+// formatter) is consistent with what was already computed.
+func parseChannels(metadata map[string]interface{}) []channelInfo {
+	if channels, ok := metadata["channels"].([]channelInfo); ok {
+		return channels
+	}
+	val, ok := metadata["published"]
+	if !ok {
+		return nil
+	}
+	info, ok := val.(map[string]interface{})["Info"].([]interface{})
+	if !ok {
+		return nil
+	}
+	channels := make([]channelInfo, 0, len(info))
+	for _, v := range info {
+		c := v.(map[string]interface{})
+		raw := c["Channel"].(string)
+		track, risk := parseChannel(raw)
+		channels = append(channels, channelInfo{
+			Track:   track,
+			Risk:    risk,
+			Current: c["Current"].(bool),
+			channel: raw,
+		})
+	}
+	sort.Sort(byTrackAndRisk(channels))
+	return channels
+}
+
+// configOption holds the data displayed for a single option in the
+// "charm show --config" table.
+type configOption struct {
+	Option      string      `json:"option" yaml:"option"`
+	Type        string      `json:"type" yaml:"type"`
+	Default     interface{} `json:"default" yaml:"default"`
+	Description string      `json:"description" yaml:"description"`
+}
+
+// parseConfigOptions extracts the charm's configuration options from the
+// "charm-config" metadata, sorted by option name. It returns nil if the
+// metadata does not contain any configuration options. If the metadata
+// already carries a precomputed "config" value (as Run sets when it has
+// already done this work), that value is returned as is.
+func parseConfigOptions(metadata map[string]interface{}) []configOption {
+	if config, ok := metadata["config"].([]configOption); ok {
+		return config
+	}
+	val, ok := metadata["charm-config"]
+	if !ok {
+		return nil
+	}
+	charmConfig, ok := val.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	options, ok := charmConfig["Options"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	config := make([]configOption, 0, len(options))
+	for name, v := range options {
+		opt := v.(map[string]interface{})
+		config = append(config, configOption{
+			Option:      name,
+			Type:        opt["Type"].(string),
+			Default:     opt["Default"],
+			Description: opt["Description"].(string),
+		})
+	}
+	sort.Sort(byConfigName(config))
+	return config
+}
+
+type byConfigName []configOption
+
+func (b byConfigName) Len() int           { return len(b) }
+func (b byConfigName) Less(i, j int) bool { return b[i].Option < b[j].Option }
+func (b byConfigName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+// resourceInfo holds the data displayed for a single row of the
+// resources table in "charm show --resources".
+type resourceInfo struct {
+	Name     string   `json:"name" yaml:"name"`
+	Type     string   `json:"type" yaml:"type"`
+	Revision int      `json:"revision" yaml:"revision"`
+	Channels []string `json:"channels" yaml:"channels"`
+}
+
+// parseResources combines the "resources" and "channel-resources" metadata
+// into a slice of resourceInfo, one entry per named resource, sorted by
+// name, listing the channels currently exposing that resource's revision.
+// "channel-resources" is set by fetchChannelResources, since the published
+// meta endpoint does not itself report per-channel resource revisions. If
+// the metadata already carries a precomputed "resources" value (as Run
+// sets when it has already done this work), that value is returned as is.
+func parseResources(metadata map[string]interface{}) []resourceInfo {
+	if resources, ok := metadata["resources"].([]resourceInfo); ok {
+		return resources
+	}
+	val, ok := metadata["resources"]
+	if !ok {
+		return nil
+	}
+	raw, ok := val.([]interface{})
+	if !ok {
+		return nil
+	}
+	resources := make([]resourceInfo, 0, len(raw))
+	for _, v := range raw {
+		r := v.(map[string]interface{})
+		resources = append(resources, resourceInfo{
+			Name:     r["Name"].(string),
+			Type:     r["Type"].(string),
+			Revision: int(r["Revision"].(float64)),
+		})
+	}
+	sort.Sort(byResourceName(resources))
+	channelResources, _ := metadata["channel-resources"].(map[string]interface{})
+	for i := range resources {
+		resources[i].Channels = channelsForResource(channelResources, resources[i].Name, resources[i].Revision)
+	}
+	return resources
+}
+
+// channelsForResource returns, in sorted order, the channels whose
+// per-channel resource list (as built by fetchChannelResources) contains
+// name at the given revision.
+func channelsForResource(channelResources map[string]interface{}, name string, revision int) []string {
+	channelNames := make([]string, 0, len(channelResources))
+	for channel := range channelResources {
+		channelNames = append(channelNames, channel)
+	}
+	sort.Strings(channelNames)
+	var channels []string
+	for _, channel := range channelNames {
+		list, ok := channelResources[channel].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, v := range list {
+			r, ok := v.(map[string]interface{})
+			if !ok || r["Name"].(string) != name {
+				continue
+			}
+			if rev, ok := r["Revision"].(float64); ok && int(rev) == revision {
+				channels = append(channels, channel)
+			}
+			break
+		}
+	}
+	return channels
+}
+
+// fetchChannelResources queries the resources meta endpoint once per
+// published channel, since the charm store reports a charm's resources
+// per channel rather than as part of the published meta endpoint itself.
+// The result is a map from "track/risk" to the raw resource list that
+// channel is publishing.
+func fetchChannelResources(client *csClient, id *charm.URL, channels []channelInfo) (map[string]interface{}, error) {
+	channelResources := make(map[string]interface{}, len(channels))
+	for _, ch := range channels {
+		var resources []interface{}
+		path := "/" + id.Path() + "/meta/resources?channel=" + url.QueryEscape(ch.channel)
+		if err := client.Get(path, &resources); err != nil {
+			return nil, errgo.Notef(err, "cannot get resources for channel %s", ch.channel)
+		}
+		channelResources[ch.key()] = resources
+	}
+	return channelResources, nil
+}
+
+// fetchChannelRevisions queries the id-revision meta endpoint once per
+// published channel, since the charm store reports a charm's revision
+// per channel rather than as part of the published meta endpoint itself.
+// The result is a map from "track/risk" to the charm revision published
+// on that channel.
+func fetchChannelRevisions(client *csClient, id *charm.URL, channels []channelInfo) (map[string]int, error) {
+	revisions := make(map[string]int, len(channels))
+	for _, ch := range channels {
+		var idRevision struct {
+			Revision int
+		}
+		path := "/" + id.Path() + "/meta/id-revision?channel=" + url.QueryEscape(ch.channel)
+		if err := client.Get(path, &idRevision); err != nil {
+			return nil, errgo.Notef(err, "cannot get revision for channel %s", ch.channel)
+		}
+		revisions[ch.key()] = idRevision.Revision
+	}
+	return revisions, nil
+}
+
+type byResourceName []resourceInfo
+
+func (b byResourceName) Len() int           { return len(b) }
+func (b byResourceName) Less(i, j int) bool { return b[i].Name < b[j].Name }
+func (b byResourceName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
 func newShowData(out io.Writer, metadada map[string]interface{}) showData {
 	sd := showData{}
 	sd.tw = tabwriter.NewWriter(out, 0, 8, 8, '\t', 0)
@@ -247,7 +579,7 @@ func newShowData(out io.Writer, metadada map[string]interface{}) showData {
 	perms := metadada["perm"].(map[string]interface{})
 	sd.read = toStringArray(perms["Read"].([]interface{}))
 	sd.write = toStringArray(perms["Write"].([]interface{}))
-	sd.channels = (metadada["published"].(map[string]interface{}))["Info"].([]interface{})
+	sd.channels = parseChannels(metadada)
 	if val, ok := metadada["charm-metadata"]; ok {
 		charmMetadata := val.(map[string]interface{})
 		sd.summary = charmMetadata["Summary"].(string)
@@ -258,6 +590,8 @@ func newShowData(out io.Writer, metadada map[string]interface{}) showData {
 	if _, ok := metadada["bundle-metadata"]; ok {
 		sd.bundle = true
 	}
+	sd.config = parseConfigOptions(metadada)
+	sd.resources = parseResources(metadada)
 	return sd
 }
 
@@ -284,20 +618,87 @@ func (s *showData) formatTabular() {
 		fmt.Fprintln(s.tw)
 	}
 	s.printChannels()
+	s.printConfig()
+	s.printResources()
 }
 
 func (s *showData) printChannels() {
-	fmt.Fprintln(s.tw, " \t ")
-	fmt.Fprint(s.tw, "CHANNEL\tCURRENT")
+	fmt.Fprintln(s.tw, " \t \t \t ")
+	fmt.Fprint(s.tw, "TRACK\tRISK\tCURRENT\tREVISION")
 	fmt.Fprintln(s.tw)
-	for _, v := range s.channels {
-		channel := v.(map[string]interface{})
-		fmt.Fprintf(s.tw, "%s\t", channel["Channel"])
-		fmt.Fprintf(s.tw, "%t\t", channel["Current"])
+	lastTrack := ""
+	for _, c := range s.channels {
+		track := c.Track
+		if track == lastTrack {
+			track = ""
+		} else {
+			lastTrack = c.Track
+		}
+		fmt.Fprintf(s.tw, "%s\t%s\t%t\t%d", track, c.Risk, c.Current, c.Revision)
 		fmt.Fprintln(s.tw)
 	}
 }
 
+// configDescriptionWidth is the number of columns a config option's
+// description is wrapped to in the tabular output.
+const configDescriptionWidth = 60
+
+func (s *showData) printConfig() {
+	if len(s.config) == 0 {
+		return
+	}
+	fmt.Fprintln(s.tw, " \t \t \t ")
+	fmt.Fprint(s.tw, "OPTION\tTYPE\tDEFAULT\tDESCRIPTION")
+	fmt.Fprintln(s.tw)
+	for _, opt := range s.config {
+		lines := wrapText(opt.Description, configDescriptionWidth)
+		if len(lines) == 0 {
+			lines = []string{""}
+		}
+		fmt.Fprintf(s.tw, "%s\t%s\t%v\t%s", opt.Option, opt.Type, opt.Default, lines[0])
+		fmt.Fprintln(s.tw)
+		for _, line := range lines[1:] {
+			fmt.Fprintf(s.tw, "\t\t\t%s", line)
+			fmt.Fprintln(s.tw)
+		}
+	}
+}
+
+func (s *showData) printResources() {
+	if len(s.resources) == 0 {
+		return
+	}
+	fmt.Fprintln(s.tw, " \t \t \t ")
+	fmt.Fprint(s.tw, "RESOURCE\tTYPE\tREVISION\tCHANNELS")
+	fmt.Fprintln(s.tw)
+	for _, r := range s.resources {
+		fmt.Fprintf(s.tw, "%s\t%s\t%d\t%s", r.Name, r.Type, r.Revision, strings.Join(r.Channels, ", "))
+		fmt.Fprintln(s.tw)
+	}
+}
+
+// wrapText splits s into lines of at most width characters, breaking
+// only on word boundaries. A word longer than width is not split; it is
+// placed on a line of its own, which is then longer than width.
+func wrapText(s string, width int) []string {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		return nil
+	}
+	var lines []string
+	line := words[0]
+	for _, word := range words[1:] {
+		if len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line = line + " " + word
+	}
+	lines = append(lines, line)
+	return lines
+}
+
 func (s *showData) printCharmMetadata() {
 	if !s.bundle {
 		fmt.Fprintf(s.tw, "%s\t%s", "Summary", s.summary)
@@ -318,3 +719,162 @@ func toStringArray(a []interface{}) []string {
 	}
 	return b
 }
+
+// metadataField maps a single capitalized field name, as returned by a
+// meta endpoint, to the lowercase, hyphenated key charm source files
+// conventionally use for the same field. nested, when set, is the field
+// mapping applied to each named entry's value within this field's map,
+// for fields whose value is itself a map of name to a capitalized-key
+// spec, such as a charm's relations or config options.
+type metadataField struct {
+	apiKey  string
+	yamlKey string
+	nested  []metadataField
+}
+
+// charmRelationFields maps a single relation entry's fields, as found in
+// a charm's Provides, Requires and Peers, to the keys conventionally used
+// in a charm's metadata.yaml.
+var charmRelationFields = []metadataField{
+	{apiKey: "Name", yamlKey: "name"},
+	{apiKey: "Role", yamlKey: "role"},
+	{apiKey: "Interface", yamlKey: "interface"},
+	{apiKey: "Optional", yamlKey: "optional"},
+	{apiKey: "Limit", yamlKey: "limit"},
+	{apiKey: "Scope", yamlKey: "scope"},
+}
+
+// charmConfigOptionFields maps a single config option entry's fields, as
+// found in charm-config's Options, to the keys conventionally used in a
+// charm's config.yaml.
+var charmConfigOptionFields = []metadataField{
+	{apiKey: "Type", yamlKey: "type"},
+	{apiKey: "Default", yamlKey: "default"},
+	{apiKey: "Description", yamlKey: "description"},
+}
+
+// charmMetadataFields maps the charm-metadata meta endpoint's fields to
+// the keys, in the order, conventionally used in a charm's metadata.yaml.
+var charmMetadataFields = []metadataField{
+	{apiKey: "Name", yamlKey: "name"},
+	{apiKey: "Summary", yamlKey: "summary"},
+	{apiKey: "Description", yamlKey: "description"},
+	{apiKey: "Subordinate", yamlKey: "subordinate"},
+	{apiKey: "SupportedSeries", yamlKey: "series"},
+	{apiKey: "Provides", yamlKey: "provides", nested: charmRelationFields},
+	{apiKey: "Requires", yamlKey: "requires", nested: charmRelationFields},
+	{apiKey: "Peers", yamlKey: "peers", nested: charmRelationFields},
+	{apiKey: "ExtraBindings", yamlKey: "extra-bindings"},
+	{apiKey: "Categories", yamlKey: "categories"},
+	{apiKey: "Tags", yamlKey: "tags"},
+	{apiKey: "PayloadClasses", yamlKey: "payloads"},
+	{apiKey: "Resources", yamlKey: "resources"},
+	{apiKey: "Terms", yamlKey: "terms"},
+	{apiKey: "MinJujuVersion", yamlKey: "min-juju-version"},
+}
+
+// charmConfigFields maps the charm-config meta endpoint's fields to the
+// keys conventionally used in a charm's config.yaml.
+var charmConfigFields = []metadataField{
+	{apiKey: "Options", yamlKey: "options", nested: charmConfigOptionFields},
+}
+
+// charmMetricsFields maps the charm-metrics meta endpoint's fields to the
+// keys conventionally used in a charm's metrics.yaml.
+var charmMetricsFields = []metadataField{
+	{apiKey: "Metrics", yamlKey: "metrics"},
+}
+
+// writeCharmFiles materializes the charm's metadata, config and metrics
+// blobs as metadata.<ext>, config.<ext> and metrics.<ext> files in dir,
+// suitable for dropping into a charm source tree.
+func writeCharmFiles(metadata map[string]interface{}, format metadecoders.Format, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errgo.Notef(err, "cannot create output directory")
+	}
+	files := []struct {
+		name   string
+		key    string
+		fields []metadataField
+	}{
+		{"metadata", "charm-metadata", charmMetadataFields},
+		{"config", "charm-config", charmConfigFields},
+		{"metrics", "charm-metrics", charmMetricsFields},
+	}
+	for _, f := range files {
+		val, ok := metadata[f.key]
+		if !ok {
+			continue
+		}
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		data, err := metadecoders.Marshal(format, toMapSlice(m, f.fields))
+		if err != nil {
+			return errgo.Notef(err, "cannot marshal %s", f.name)
+		}
+		outPath := filepath.Join(dir, f.name+"."+string(format))
+		if err := ioutil.WriteFile(outPath, data, 0644); err != nil {
+			return errgo.Notef(err, "cannot write %s", outPath)
+		}
+	}
+	return nil
+}
+
+// toMapSlice converts m into a yaml.MapSlice, renaming and ordering the
+// keys named in fields first (in that order, when present) and appending
+// any remaining keys, unchanged, in alphabetical order. This lets callers
+// both translate the charm store's capitalized field names to the
+// lowercase keys charm source files use, and control key ordering in
+// formats, like YAML, where it is conventionally significant.
+func toMapSlice(m map[string]interface{}, fields []metadataField) yaml.MapSlice {
+	seen := make(map[string]bool, len(fields))
+	items := make(yaml.MapSlice, 0, len(m))
+	for _, f := range fields {
+		if val, ok := m[f.apiKey]; ok {
+			if f.nested != nil {
+				val = mapValuesToMapSlice(val, f.nested)
+			}
+			items = append(items, yaml.MapItem{Key: f.yamlKey, Value: val})
+			seen[f.apiKey] = true
+		}
+	}
+	rest := make([]string, 0, len(m))
+	for key := range m {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	for _, key := range rest {
+		items = append(items, yaml.MapItem{Key: key, Value: m[key]})
+	}
+	return items
+}
+
+// mapValuesToMapSlice converts a map of name to capitalized-key spec, such
+// as a charm's relations or config options, into an ordered yaml.MapSlice
+// keyed by name, with each spec's own keys renamed and ordered according
+// to fields.
+func mapValuesToMapSlice(val interface{}, fields []metadataField) interface{} {
+	specs, ok := val.(map[string]interface{})
+	if !ok {
+		return val
+	}
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	items := make(yaml.MapSlice, 0, len(specs))
+	for _, name := range names {
+		spec, ok := specs[name].(map[string]interface{})
+		if !ok {
+			items = append(items, yaml.MapItem{Key: name, Value: specs[name]})
+			continue
+		}
+		items = append(items, yaml.MapItem{Key: name, Value: toMapSlice(spec, fields)})
+	}
+	return items
+}
@@ -0,0 +1,187 @@
+// Copyright 2017 Canonical Ltd.
+// Licensed under the GPLv3, see LICENCE file for details.
+
+package charmcmd
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseChannel(t *testing.T) {
+	tests := []struct {
+		s         string
+		wantTrack string
+		wantRisk  string
+	}{
+		{"stable", "latest", "stable"},
+		{"latest/edge", "latest", "edge"},
+		{"2.0/candidate", "2.0", "candidate"},
+	}
+	for _, test := range tests {
+		track, risk := parseChannel(test.s)
+		if track != test.wantTrack || risk != test.wantRisk {
+			t.Errorf("parseChannel(%q) = (%q, %q), want (%q, %q)", test.s, track, risk, test.wantTrack, test.wantRisk)
+		}
+	}
+}
+
+func TestParseChannels(t *testing.T) {
+	metadata := map[string]interface{}{
+		"published": map[string]interface{}{
+			"Info": []interface{}{
+				map[string]interface{}{"Channel": "latest/edge", "Current": false},
+				map[string]interface{}{"Channel": "latest/stable", "Current": true},
+				map[string]interface{}{"Channel": "2.0/stable", "Current": true},
+			},
+		},
+	}
+	got := parseChannels(metadata)
+	want := []channelInfo{
+		{Track: "2.0", Risk: "stable", Current: true, channel: "2.0/stable"},
+		{Track: "latest", Risk: "stable", Current: true, channel: "latest/stable"},
+		{Track: "latest", Risk: "edge", Current: false, channel: "latest/edge"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseChannels returned %#v, want %#v", got, want)
+	}
+}
+
+func TestParseChannelsUsesPrecomputedValue(t *testing.T) {
+	precomputed := []channelInfo{{Track: "latest", Risk: "stable", Current: true}}
+	metadata := map[string]interface{}{
+		"channels": precomputed,
+		// A "published" entry that would, if parsed, yield a different
+		// result, to prove the precomputed value takes precedence.
+		"published": map[string]interface{}{
+			"Info": []interface{}{
+				map[string]interface{}{"Channel": "latest/edge", "Current": false},
+			},
+		},
+	}
+	got := parseChannels(metadata)
+	if !reflect.DeepEqual(got, precomputed) {
+		t.Errorf("parseChannels returned %#v, want precomputed %#v", got, precomputed)
+	}
+}
+
+func TestParseChannelsNoPublished(t *testing.T) {
+	if got := parseChannels(map[string]interface{}{}); got != nil {
+		t.Errorf("parseChannels with no published metadata = %#v, want nil", got)
+	}
+}
+
+func TestParseConfigOptions(t *testing.T) {
+	metadata := map[string]interface{}{
+		"charm-config": map[string]interface{}{
+			"Options": map[string]interface{}{
+				"blog-title": map[string]interface{}{
+					"Type":        "string",
+					"Default":     "My Blog",
+					"Description": "The title of the blog.",
+				},
+				"debug": map[string]interface{}{
+					"Type":        "boolean",
+					"Default":     false,
+					"Description": "Enable debug logging.",
+				},
+			},
+		},
+	}
+	got := parseConfigOptions(metadata)
+	want := []configOption{
+		{Option: "blog-title", Type: "string", Default: "My Blog", Description: "The title of the blog."},
+		{Option: "debug", Type: "boolean", Default: false, Description: "Enable debug logging."},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseConfigOptions returned %#v, want %#v", got, want)
+	}
+}
+
+func TestParseResources(t *testing.T) {
+	metadata := map[string]interface{}{
+		"resources": []interface{}{
+			map[string]interface{}{"Name": "website", "Type": "file", "Revision": float64(3)},
+			map[string]interface{}{"Name": "image", "Type": "oci-image", "Revision": float64(1)},
+		},
+		"channel-resources": map[string]interface{}{
+			"latest/stable": []interface{}{
+				map[string]interface{}{"Name": "website", "Revision": float64(3)},
+			},
+			"latest/edge": []interface{}{
+				map[string]interface{}{"Name": "website", "Revision": float64(2)},
+			},
+		},
+	}
+	got := parseResources(metadata)
+	want := []resourceInfo{
+		{Name: "image", Type: "oci-image", Revision: 1, Channels: nil},
+		{Name: "website", Type: "file", Revision: 3, Channels: []string{"latest/stable"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseResources returned %#v, want %#v", got, want)
+	}
+}
+
+func TestToMapSlice(t *testing.T) {
+	m := map[string]interface{}{
+		"Name":    "wordpress",
+		"Summary": "a blog engine",
+		"Unknown": "extra",
+	}
+	fields := []metadataField{
+		{apiKey: "Name", yamlKey: "name"},
+		{apiKey: "Summary", yamlKey: "summary"},
+	}
+	got := toMapSlice(m, fields)
+	if len(got) != 3 {
+		t.Fatalf("toMapSlice returned %d items, want 3", len(got))
+	}
+	if got[0].Key != "name" || got[0].Value != "wordpress" {
+		t.Errorf("item 0 = %#v, want name/wordpress", got[0])
+	}
+	if got[1].Key != "summary" || got[1].Value != "a blog engine" {
+		t.Errorf("item 1 = %#v, want summary/a blog engine", got[1])
+	}
+	if got[2].Key != "Unknown" || got[2].Value != "extra" {
+		t.Errorf("item 2 = %#v, want Unknown/extra", got[2])
+	}
+}
+
+// TestToMapSliceNested checks that fields whose value is a map of name to
+// capitalized-key spec, such as a charm's Provides relations, have their
+// nested keys renamed as well, not just the top-level keys.
+func TestToMapSliceNested(t *testing.T) {
+	m := map[string]interface{}{
+		"Provides": map[string]interface{}{
+			"website": map[string]interface{}{
+				"Name":      "website",
+				"Role":      "provider",
+				"Interface": "http",
+				"Optional":  false,
+				"Limit":     0,
+				"Scope":     "global",
+			},
+		},
+	}
+	fields := []metadataField{
+		{apiKey: "Provides", yamlKey: "provides", nested: charmRelationFields},
+	}
+	got := toMapSlice(m, fields)
+	if len(got) != 1 || got[0].Key != "provides" {
+		t.Fatalf("toMapSlice returned %#v, want single provides item", got)
+	}
+	relations, ok := got[0].Value.(yaml.MapSlice)
+	if !ok || len(relations) != 1 || relations[0].Key != "website" {
+		t.Fatalf("provides value = %#v, want single website entry", got[0].Value)
+	}
+	website, ok := relations[0].Value.(yaml.MapSlice)
+	if !ok {
+		t.Fatalf("website value = %#v, want yaml.MapSlice", relations[0].Value)
+	}
+	if website[0].Key != "name" || website[2].Key != "interface" || website[2].Value != "http" {
+		t.Errorf("website relation = %#v, want lowercased keys with interface=http", website)
+	}
+}
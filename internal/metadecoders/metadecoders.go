@@ -0,0 +1,160 @@
+// Copyright 2017 Canonical Ltd.
+// Licensed under the GPLv3, see LICENCE file for details.
+
+// Package metadecoders provides Marshal and Unmarshal functions that
+// work across the handful of on-disk formats charm metadata is commonly
+// expressed in, so that commands do not need to duplicate per-format
+// marshaling logic.
+package metadecoders
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/errgo.v1"
+	"gopkg.in/yaml.v2"
+)
+
+// Format represents one of the supported on-disk metadata formats.
+type Format string
+
+const (
+	YAML Format = "yaml"
+	JSON Format = "json"
+	TOML Format = "toml"
+)
+
+// Marshal renders v in the given format. If v is a yaml.MapSlice, its key
+// order is preserved by the YAML and JSON encoders; the TOML encoder has
+// no notion of map ordering, so a MapSlice is flattened to a plain map
+// before being handed to it.
+func Marshal(format Format, v interface{}) ([]byte, error) {
+	switch format {
+	case YAML:
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return nil, errgo.Notef(err, "cannot marshal to YAML")
+		}
+		return data, nil
+	case JSON:
+		data, err := json.MarshalIndent(toOrderedJSON(v), "", "    ")
+		if err != nil {
+			return nil, errgo.Notef(err, "cannot marshal to JSON")
+		}
+		return data, nil
+	case TOML:
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(flatten(v)); err != nil {
+			return nil, errgo.Notef(err, "cannot marshal to TOML")
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, errgo.Newf("unknown format %q", format)
+}
+
+// Unmarshal parses data in the given format into v.
+func Unmarshal(format Format, data []byte, v interface{}) error {
+	switch format {
+	case YAML:
+		if err := yaml.Unmarshal(data, v); err != nil {
+			return errgo.Notef(err, "cannot unmarshal YAML")
+		}
+		return nil
+	case JSON:
+		if err := json.Unmarshal(data, v); err != nil {
+			return errgo.Notef(err, "cannot unmarshal JSON")
+		}
+		return nil
+	case TOML:
+		if err := toml.Unmarshal(data, v); err != nil {
+			return errgo.Notef(err, "cannot unmarshal TOML")
+		}
+		return nil
+	}
+	return errgo.Newf("unknown format %q", format)
+}
+
+// flatten recursively replaces yaml.MapSlice values with plain
+// map[string]interface{} values, for encoders that have no notion of
+// map key order.
+func flatten(v interface{}) interface{} {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		m := make(map[string]interface{}, len(v))
+		for _, item := range v {
+			key, _ := item.Key.(string)
+			m[key] = flatten(item.Value)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, item := range v {
+			a[i] = flatten(item)
+		}
+		return a
+	default:
+		return v
+	}
+}
+
+// toOrderedJSON recursively converts a yaml.MapSlice into an orderedMap,
+// which marshals to JSON preserving key order; other values are returned
+// unchanged since encoding/json already handles them as desired.
+func toOrderedJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		om := make(orderedMap, 0, len(v))
+		for _, item := range v {
+			key, _ := item.Key.(string)
+			om = append(om, orderedMapItem{
+				Key:   key,
+				Value: toOrderedJSON(item.Value),
+			})
+		}
+		return om
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, item := range v {
+			a[i] = toOrderedJSON(item)
+		}
+		return a
+	default:
+		return v
+	}
+}
+
+// orderedMapItem is a single key/value pair of an orderedMap.
+type orderedMapItem struct {
+	Key   string
+	Value interface{}
+}
+
+// orderedMap is a JSON object that marshals its entries in the order
+// they were added, rather than the alphabetical order that encoding/json
+// imposes on plain maps.
+type orderedMap []orderedMapItem
+
+// MarshalJSON implements json.Marshaler.
+func (om orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, item := range om {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(item.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := json.Marshal(item.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2017 Canonical Ltd.
+// Licensed under the GPLv3, see LICENCE file for details.
+
+package metadecoders
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+// TestRoundTrip marshals a yaml.MapSlice in each supported format and
+// unmarshals the result back into a map, checking that no data is lost
+// in the round trip.
+func TestRoundTrip(t *testing.T) {
+	in := yaml.MapSlice{
+		{Key: "name", Value: "wordpress"},
+		{Key: "summary", Value: "a blog engine"},
+		{Key: "series", Value: []interface{}{"trusty", "xenial"}},
+		{Key: "subordinate", Value: false},
+	}
+	for _, format := range []Format{YAML, JSON, TOML} {
+		data, err := Marshal(format, in)
+		if err != nil {
+			t.Fatalf("%s: Marshal failed: %v", format, err)
+		}
+		var out map[string]interface{}
+		if err := Unmarshal(format, data, &out); err != nil {
+			t.Fatalf("%s: Unmarshal failed: %v", format, err)
+		}
+		want := map[string]interface{}{
+			"name":        "wordpress",
+			"summary":     "a blog engine",
+			"series":      []interface{}{"trusty", "xenial"},
+			"subordinate": false,
+		}
+		if !reflect.DeepEqual(out, want) {
+			t.Errorf("%s: round trip mismatch\ngot:  %#v\nwant: %#v", format, out, want)
+		}
+	}
+}
+
+func TestMarshalUnknownFormat(t *testing.T) {
+	if _, err := Marshal("bogus", yaml.MapSlice{}); err == nil {
+		t.Errorf("Marshal with unknown format unexpectedly succeeded")
+	}
+}
+
+func TestUnmarshalUnknownFormat(t *testing.T) {
+	var out map[string]interface{}
+	if err := Unmarshal("bogus", []byte("{}"), &out); err == nil {
+		t.Errorf("Unmarshal with unknown format unexpectedly succeeded")
+	}
+}
+
+func TestMarshalYAMLPreservesOrder(t *testing.T) {
+	in := yaml.MapSlice{
+		{Key: "z", Value: 1},
+		{Key: "a", Value: 2},
+	}
+	data, err := Marshal(YAML, in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := "z: 1\na: 2\n"
+	if string(data) != want {
+		t.Errorf("Marshal(YAML) = %q, want %q", data, want)
+	}
+}